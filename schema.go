@@ -1,11 +1,15 @@
 package schema
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
 	"os"
 	"reflect"
 	"regexp"
-	"strconv"
+	"sort"
 	"strings"
 )
 
@@ -16,29 +20,163 @@ import (
 // checking process the error happened. Checkers like OneOf may continue
 // with an alternative, for instance.
 type Checker interface {
-	Coerce(v interface{}, path []string) (newv interface{}, err os.Error)
+	Coerce(v interface{}, path []PathStep) (newv interface{}, err os.Error)
 }
 
-type error struct {
-	want string
-	got interface{}
-	path    []string
+// PathStep is one element of an Error's Path, identifying a single
+// step taken from the root of the coerced value to reach the one
+// that failed.
+type PathStep interface {
+	String() string
 }
 
-func (e error) String() string {
-	var path string
-	if e.path[0] == "." {
-		path = strings.Join(e.path[1:], "")
-	} else {
-		path = strings.Join(e.path, "")
+// FieldStep identifies a named field of a FieldMap or Struct value.
+type FieldStep struct {
+	Name string
+}
+
+func (s FieldStep) String() string { return "." + s.Name }
+
+// IndexStep identifies an element of a List value by position.
+type IndexStep struct {
+	Index int
+}
+
+func (s IndexStep) String() string { return fmt.Sprintf("[%d]", s.Index) }
+
+// KeyStep identifies an entry of a Map value by key.
+type KeyStep struct {
+	Key interface{}
+}
+
+func (s KeyStep) String() string { return fmt.Sprintf("[%#v]", s.Key) }
+
+// formatPath renders a Path as it should appear in an error message,
+// e.g. ".name" or ".inner.host". A FieldStep already renders its own
+// leading dot, so an empty path (the root value itself) falls back to
+// a bare ".".
+func formatPath(path []PathStep) string {
+	if len(path) == 0 {
+		return "."
+	}
+	s := ""
+	for _, step := range path {
+		s += step.String()
+	}
+	return s
+}
+
+// Error reports that a value failed to coerce against its Checker,
+// identifying where in the input value the failure happened.
+type Error struct {
+	Path []PathStep
+	Want string
+	Got  interface{}
+}
+
+func (e Error) String() string {
+	path := formatPath(e.Path)
+	if e.Want == "" {
+		if e.Got == nil {
+			return fmt.Sprintf("%s: unsupported value", path)
+		}
+		// Got holds the message of some other error (e.g. a WithHook
+		// fn failure) that doesn't fit the expected/got shape below.
+		return fmt.Sprintf("%s: %v", path, e.Got)
 	}
-	if e.want == "" {
-		return fmt.Sprintf("%s: unsupported value", path)
+	if e.Got == nil {
+		return fmt.Sprintf("%s: expected %s, got nothing", path, e.Want)
 	}
-	if e.got == nil {
-		return fmt.Sprintf("%s: expected %s, got nothing", path, e.want)
+	return fmt.Sprintf("%s: expected %s, got %#v", path, e.Want, e.Got)
+}
+
+// MultiError holds every error accumulated while coercing a single
+// value, for instance one entry per field of a FieldMap or element
+// of a List that failed to coerce, rather than just the first one
+// encountered.
+type MultiError struct {
+	Errors []os.Error
+}
+
+func (e *MultiError) String() string {
+	lines := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		lines[i] = err.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// add appends err to e, flattening err into e.Errors directly if it
+// is itself a *MultiError so that MultiErrors never nest.
+func (e *MultiError) add(err os.Error) {
+	if err == nil {
+		return
+	}
+	if me, ok := err.(*MultiError); ok {
+		e.Errors = append(e.Errors, me.Errors...)
+		return
+	}
+	e.Errors = append(e.Errors, err)
+}
+
+// pathCopy returns a copy of path, so that callers that mutate a
+// shared path slice across iterations (e.g. to set the current
+// index or key) can hand out a stable copy to every accumulated
+// error without later iterations corrupting it.
+func pathCopy(path []PathStep) []PathStep {
+	return append([]PathStep{}, path...)
+}
+
+// CoerceAll coerces v with checker, and returns every error found as
+// a flat, strongly-typed slice rather than the os.Error value that
+// Checker.Coerce returns. A nil slice means v coerced successfully.
+func CoerceAll(checker Checker, v interface{}) (interface{}, []Error) {
+	newv, err := checker.Coerce(v, nil)
+	if err == nil {
+		return newv, nil
+	}
+	return nil, flattenErrors(err)
+}
+
+func flattenErrors(err os.Error) []Error {
+	if me, ok := err.(*MultiError); ok {
+		errs := make([]Error, 0, len(me.Errors))
+		for _, e := range me.Errors {
+			errs = append(errs, flattenErrors(e)...)
+		}
+		return errs
+	}
+	if e, ok := err.(Error); ok {
+		return []Error{e}
+	}
+	// Some other kind of os.Error (e.g. one returned straight from a
+	// WithHook fn that isn't path-aware). Keep its message rather
+	// than discarding it: Error.String treats a nil Got specially,
+	// so stash the message as a string instead.
+	return []Error{{Want: "", Got: err.String()}}
+}
+
+// Report coerces got against schema, a Checker, and writes a
+// side-by-side view to w of every failing path found: the path, what
+// the schema expected there, and what was actually found.
+func Report(w io.Writer, got, schema interface{}) {
+	checker, ok := schema.(Checker)
+	if !ok {
+		panic("schema.Report requires a Checker")
+	}
+	_, errs := CoerceAll(checker, got)
+	for _, e := range errs {
+		path := formatPath(e.Path)
+		want := e.Want
+		if want == "" {
+			want = "a valid value"
+		}
+		gotDesc := "nothing"
+		if e.Got != nil {
+			gotDesc = fmt.Sprintf("%#v", e.Got)
+		}
+		fmt.Fprintf(w, "%s:\n\texpected: %s\n\tgot:      %s\n", path, want, gotDesc)
 	}
-	return fmt.Sprintf("%s: expected %s, got %#v", path, e.want, e.got)
 }
 
 // Any returns a Checker that succeeds with any input value and
@@ -49,11 +187,10 @@ func Any() Checker {
 
 type anyC struct{}
 
-func (c anyC) Coerce(v interface{}, path []string) (interface{}, os.Error) {
+func (c anyC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
 	return v, nil
 }
 
-
 // Const returns a Checker that only succeeds if the input matches
 // value exactly.  The value is compared with reflect.DeepEqual.
 func Const(value interface{}) Checker {
@@ -64,11 +201,11 @@ type constC struct {
 	value interface{}
 }
 
-func (c constC) Coerce(v interface{}, path []string) (interface{}, os.Error) {
+func (c constC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
 	if reflect.DeepEqual(v, c.value) {
 		return v, nil
 	}
-	return nil, error{fmt.Sprintf("%#v", c.value), v, path}
+	return nil, Error{path, fmt.Sprintf("%#v", c.value), v}
 }
 
 // OneOf returns a Checker that attempts to Coerce the value with each
@@ -83,14 +220,64 @@ type oneOfC struct {
 	options []Checker
 }
 
-func (c oneOfC) Coerce(v interface{}, path []string) (interface{}, os.Error) {
+func (c oneOfC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
 	for _, o := range c.options {
 		newv, err := o.Coerce(v, path)
 		if err == nil {
 			return newv, nil
 		}
 	}
-	return nil, error{path: path}
+	return nil, Error{Path: path}
+}
+
+// Default returns a Checker that uses value in place of the input
+// whenever the input is nil, and otherwise delegates to inner. It is
+// most useful paired with FieldMap's Optional list, so that a field
+// left out of the input map still produces a value rather than being
+// silently dropped from the coerced result.
+func Default(value interface{}, inner Checker) Checker {
+	return defaultC{value, inner}
+}
+
+type defaultC struct {
+	value interface{}
+	inner Checker
+}
+
+func (c defaultC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
+	if v == nil {
+		v = c.value
+	}
+	return c.inner.Coerce(v, path)
+}
+
+// WithHook returns a Checker that first coerces the input with inner
+// and, if that succeeds, passes the result through fn for arbitrary
+// post-processing (e.g. normalizing a string to lowercase, or parsing
+// a duration). The value returned by fn becomes the result of the
+// WithHook checker itself; if fn returns an error instead, the result
+// is an Error at the current path that carries fn's error message.
+func WithHook(inner Checker, fn func(interface{}, []PathStep) (interface{}, os.Error)) Checker {
+	return hookC{inner, fn}
+}
+
+type hookC struct {
+	inner Checker
+	fn    func(interface{}, []PathStep) (interface{}, os.Error)
+}
+
+func (c hookC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
+	newv, err := c.inner.Coerce(v, path)
+	if err != nil {
+		return nil, err
+	}
+	hookv, err := c.fn(newv, path)
+	if err != nil {
+		// fn's error isn't path-aware; wrap it in an Error that is,
+		// keeping its message in Got rather than discarding it.
+		return nil, Error{path, "", err.String()}
+	}
+	return hookv, nil
 }
 
 // Bool returns a Checker that accepts boolean values only.
@@ -100,11 +287,11 @@ func Bool() Checker {
 
 type boolC struct{}
 
-func (c boolC) Coerce(v interface{}, path []string) (interface{}, os.Error) {
-	if reflect.TypeOf(v).Kind() == reflect.Bool {
+func (c boolC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
+	if v != nil && reflect.TypeOf(v).Kind() == reflect.Bool {
 		return v, nil
 	}
-	return nil, error{"bool", v, path}
+	return nil, Error{path, "bool", v}
 }
 
 // Int returns a Checker that accepts any integer value, and returns
@@ -115,7 +302,10 @@ func Int() Checker {
 
 type intC struct{}
 
-func (c intC) Coerce(v interface{}, path []string) (interface{}, os.Error) {
+func (c intC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
+	if v == nil {
+		return nil, Error{path, "int", v}
+	}
 	switch reflect.TypeOf(v).Kind() {
 	case reflect.Int:
 	case reflect.Int8:
@@ -123,11 +313,34 @@ func (c intC) Coerce(v interface{}, path []string) (interface{}, os.Error) {
 	case reflect.Int32:
 	case reflect.Int64:
 	default:
-		return nil, error{"int", v, path}
+		return nil, Error{path, "int", v}
 	}
 	return reflect.ValueOf(v).Int(), nil
 }
 
+// IntRange returns a Checker that accepts any integer value within
+// min and max inclusive, and returns it typed as an int64, in the
+// same way as Int.
+func IntRange(min, max int64) Checker {
+	return intRangeC{min, max}
+}
+
+type intRangeC struct {
+	min, max int64
+}
+
+func (c intRangeC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
+	newv, err := Int().Coerce(v, path)
+	if err != nil {
+		return nil, err
+	}
+	n := newv.(int64)
+	if n < c.min || n > c.max {
+		return nil, Error{path, fmt.Sprintf("int in range [%d, %d]", c.min, c.max), v}
+	}
+	return n, nil
+}
+
 // Int returns a Checker that accepts any float value, and returns
 // the same value typed as a float64.
 func Float() Checker {
@@ -136,16 +349,41 @@ func Float() Checker {
 
 type floatC struct{}
 
-func (c floatC) Coerce(v interface{}, path []string) (interface{}, os.Error) {
+func (c floatC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
+	if v == nil {
+		return nil, Error{path, "float", v}
+	}
 	switch reflect.TypeOf(v).Kind() {
 	case reflect.Float32:
 	case reflect.Float64:
 	default:
-		return nil, error{"float", v, path}
+		return nil, Error{path, "float", v}
 	}
 	return reflect.ValueOf(v).Float(), nil
 }
 
+// FloatRange returns a Checker that accepts any float value within
+// min and max inclusive, and returns it typed as a float64, in the
+// same way as Float.
+func FloatRange(min, max float64) Checker {
+	return floatRangeC{min, max}
+}
+
+type floatRangeC struct {
+	min, max float64
+}
+
+func (c floatRangeC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
+	newv, err := Float().Coerce(v, path)
+	if err != nil {
+		return nil, err
+	}
+	n := newv.(float64)
+	if n < c.min || n > c.max {
+		return nil, Error{path, fmt.Sprintf("float in range [%v, %v]", c.min, c.max), v}
+	}
+	return n, nil
+}
 
 // String returns a Checker that accepts a string value only and returns
 // it unprocessed.
@@ -155,11 +393,11 @@ func String() Checker {
 
 type stringC struct{}
 
-func (c stringC) Coerce(v interface{}, path []string) (interface{}, os.Error) {
-	if reflect.TypeOf(v).Kind() == reflect.String {
+func (c stringC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
+	if v != nil && reflect.TypeOf(v).Kind() == reflect.String {
 		return reflect.ValueOf(v).String(), nil
 	}
-	return nil, error{"string", v, path}
+	return nil, Error{path, "string", v}
 }
 
 func SimpleRegexp() Checker {
@@ -168,25 +406,75 @@ func SimpleRegexp() Checker {
 
 type sregexpC struct{}
 
-func (c sregexpC) Coerce(v interface{}, path []string) (interface{}, os.Error) {
+func (c sregexpC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
 	// XXX The regexp package happens to be extremely simple right now.
 	//     Once exp/regexp goes mainstream, we'll have to update this
 	//     logic to use a more widely accepted regexp subset.
-	if reflect.TypeOf(v).Kind() == reflect.String {
+	if v != nil && reflect.TypeOf(v).Kind() == reflect.String {
 		s := reflect.ValueOf(v).String()
 		_, err := regexp.Compile(s)
 		if err != nil {
-			return nil, error{"valid regexp", s, path}
+			return nil, Error{path, "valid regexp", s}
 		}
 		return v, nil
 	}
-	return nil, error{"regexp string", v, path}
+	return nil, Error{path, "regexp string", v}
+}
+
+// StringMatching returns a Checker that accepts a string value
+// matching the given regular expression, which is compiled once when
+// the Checker is constructed.
+func StringMatching(pattern string) Checker {
+	expr, err := regexp.Compile(pattern)
+	if err != nil {
+		panic("schema.StringMatching: invalid pattern: " + pattern)
+	}
+	return stringMatchingC{expr}
+}
+
+type stringMatchingC struct {
+	expr *regexp.Regexp
+}
+
+func (c stringMatchingC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
+	newv, err := String().Coerce(v, path)
+	if err != nil {
+		return nil, err
+	}
+	s := newv.(string)
+	if !c.expr.MatchString(s) {
+		return nil, Error{path, fmt.Sprintf("string matching %q", c.expr.String()), v}
+	}
+	return s, nil
+}
+
+// StringLength returns a Checker that accepts a string value with
+// length within min and max inclusive.
+func StringLength(min, max int) Checker {
+	return stringLengthC{min, max}
+}
+
+type stringLengthC struct {
+	min, max int
+}
+
+func (c stringLengthC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
+	newv, err := String().Coerce(v, path)
+	if err != nil {
+		return nil, err
+	}
+	s := newv.(string)
+	if len(s) < c.min || len(s) > c.max {
+		return nil, Error{path, fmt.Sprintf("string of length [%d, %d]", c.min, c.max), v}
+	}
+	return s, nil
 }
 
 // String returns a Checker that accepts a slice value with values
 // that are processed with the elem checker.  If any element of the
-// provided slice value fails to be processed, processing will stop
-// and return with the obtained error.
+// provided slice value fails to be processed, processing continues
+// over the remaining elements and a *MultiError with every failure
+// found is returned.
 func List(elem Checker) Checker {
 	return listC{elem}
 }
@@ -195,82 +483,143 @@ type listC struct {
 	elem Checker
 }
 
-func (c listC) Coerce(v interface{}, path []string) (interface{}, os.Error) {
+func (c listC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Slice {
-		return nil, error{"list", v, path}
+		return nil, Error{path, "list", v}
 	}
 
-	path = append(path, "[", "?", "]")
-
+	var errs MultiError
 	l := rv.Len()
 	out := make([]interface{}, 0, l)
 	for i := 0; i != l; i++ {
-		path[len(path)-2] = strconv.Itoa(i)
-		elem, err := c.elem.Coerce(rv.Index(i).Interface(), path)
+		elemPath := append(pathCopy(path), IndexStep{i})
+		elem, err := c.elem.Coerce(rv.Index(i).Interface(), elemPath)
 		if err != nil {
-			return nil, err
+			errs.add(err)
+			continue
 		}
 		out = append(out, elem)
 	}
+	if len(errs.Errors) > 0 {
+		return nil, &errs
+	}
 	return out, nil
 }
 
+// ListLength returns a Checker that accepts a slice value with
+// length within min and max inclusive, leaving the slice and its
+// elements unprocessed.
+func ListLength(min, max int) Checker {
+	return listLengthC{min, max}
+}
+
+type listLengthC struct {
+	min, max int
+}
+
+func (c listLengthC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, Error{path, "list", v}
+	}
+	if rv.Len() < c.min || rv.Len() > c.max {
+		return nil, Error{path, fmt.Sprintf("list of length [%d, %d]", c.min, c.max), v}
+	}
+	return v, nil
+}
+
 // Map returns a Checker that accepts a map value. Every key and value
 // in the map are processed with the respective checker, and if any
-// value fails to be coerced, processing stops and returns with the
-// underlying error.
+// entry fails to be coerced, processing continues over the remaining
+// entries and a *MultiError with every failure found is returned.
 func Map(key Checker, value Checker) Checker {
 	return mapC{key, value}
 }
 
 type mapC struct {
-	key Checker
+	key   Checker
 	value Checker
 }
 
-func (c mapC) Coerce(v interface{}, path []string) (interface{}, os.Error) {
+func (c mapC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Map {
-		return nil, error{"map", v, path}
+		return nil, Error{path, "map", v}
 	}
 
-	vpath := append(path, ".", "?")
-
+	var errs MultiError
 	l := rv.Len()
 	out := make(map[interface{}]interface{}, l)
 	keys := rv.MapKeys()
 	for i := 0; i != l; i++ {
 		k := keys[i]
-		newk, err := c.key.Coerce(k.Interface(), path)
+		newk, err := c.key.Coerce(k.Interface(), pathCopy(path))
 		if err != nil {
-			return nil, err
+			errs.add(err)
+			continue
 		}
-		vpath[len(vpath)-1] = fmt.Sprint(k.Interface())
+		vpath := append(pathCopy(path), KeyStep{k.Interface()})
 		newv, err := c.value.Coerce(rv.MapIndex(k).Interface(), vpath)
 		if err != nil {
-			return nil, err
+			errs.add(err)
+			continue
 		}
 		out[newk] = newv
 	}
+	if len(errs.Errors) > 0 {
+		return nil, &errs
+	}
 	return out, nil
 }
 
+// MapLength returns a Checker that accepts a map value with length
+// within min and max inclusive, leaving the map and its entries
+// unprocessed.
+func MapLength(min, max int) Checker {
+	return mapLengthC{min, max}
+}
+
+type mapLengthC struct {
+	min, max int
+}
+
+func (c mapLengthC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map {
+		return nil, Error{path, "map", v}
+	}
+	if rv.Len() < c.min || rv.Len() > c.max {
+		return nil, Error{path, fmt.Sprintf("map of length [%d, %d]", c.min, c.max), v}
+	}
+	return v, nil
+}
+
 type Fields map[string]Checker
 type Optional []string
 
 // FieldMap returns a Checker that accepts a map value with defined
 // string keys. Every key has an independent checker associated,
 // and processing will only succeed if all the values succeed
-// individually. If a field fails to be processed, processing stops
-// and returns with the underlying error.
+// individually. If one or more fields fail to be processed,
+// processing continues over the remaining fields and a *MultiError
+// with every failure found is returned.
 func FieldMap(fields Fields, optional Optional) Checker {
-	return fieldMapC{fields, optional}
+	return fieldMapC{fields, optional, false}
+}
+
+// StrictFieldMap returns a Checker like the one returned by FieldMap,
+// with the additional restriction that any key found in the input map
+// that isn't declared in fields is reported as an error rather than
+// silently ignored.
+func StrictFieldMap(fields Fields, optional Optional) Checker {
+	return fieldMapC{fields, optional, true}
 }
 
 type fieldMapC struct {
-	fields Fields
+	fields   Fields
 	optional []string
+	strict   bool
 }
 
 func (c fieldMapC) isOptional(key string) bool {
@@ -282,31 +631,60 @@ func (c fieldMapC) isOptional(key string) bool {
 	return false
 }
 
-func (c fieldMapC) Coerce(v interface{}, path []string) (interface{}, os.Error) {
+func (c fieldMapC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Map {
-		return nil, error{"map", v, path}
+		return nil, Error{path, "map", v}
 	}
 
-	vpath := append(path, ".", "?")
+	names := make([]string, 0, len(c.fields))
+	for k := range c.fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
 
+	var errs MultiError
 	l := rv.Len()
 	out := make(map[string]interface{}, l)
-	for k, checker := range c.fields {
-		vpath[len(vpath)-1] = k
+	for _, k := range names {
+		checker := c.fields[k]
 		var value interface{}
 		valuev := rv.MapIndex(reflect.ValueOf(k))
 		if valuev.IsValid() {
 			value = valuev.Interface()
 		} else if c.isOptional(k) {
-			continue
+			// A Default checker still wants the chance to substitute
+			// its value, so only skip the field entirely when there's
+			// no default to apply.
+			if _, ok := checker.(defaultC); !ok {
+				continue
+			}
 		}
-		newv, err := checker.Coerce(value, vpath)
+		fpath := append(pathCopy(path), FieldStep{k})
+		newv, err := checker.Coerce(value, fpath)
 		if err != nil {
-			return nil, err
+			errs.add(err)
+			continue
 		}
 		out[k] = newv
 	}
+	if c.strict {
+		var unknown []string
+		for _, k := range rv.MapKeys() {
+			key := fmt.Sprint(k.Interface())
+			if _, ok := c.fields[key]; !ok {
+				unknown = append(unknown, key)
+			}
+		}
+		sort.Strings(unknown)
+		for _, key := range unknown {
+			fpath := append(pathCopy(path), FieldStep{key})
+			errs.add(Error{fpath, "no unknown fields", key})
+		}
+	}
+	if len(errs.Errors) > 0 {
+		return nil, &errs
+	}
 	return out, nil
 }
 
@@ -332,13 +710,13 @@ func FieldMapSet(selector string, maps []Checker) Checker {
 
 type mapSetC struct {
 	selector string
-	fmaps []fieldMapC
+	fmaps    []fieldMapC
 }
 
-func (c mapSetC) Coerce(v interface{}, path []string) (interface{}, os.Error) {
+func (c mapSetC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Map {
-		return nil, error{"map", v, path}
+		return nil, Error{path, "map", v}
 	}
 
 	var selector interface{}
@@ -353,5 +731,353 @@ func (c mapSetC) Coerce(v interface{}, path []string) (interface{}, os.Error) {
 			return fmap.Coerce(v, path)
 		}
 	}
-	return nil, error{"supported selector", selector, append(path, ".", c.selector)}
-}
\ No newline at end of file
+	return nil, Error{append(pathCopy(path), FieldStep{c.selector}), "supported selector", selector}
+}
+
+// Struct returns a Checker that accepts a map value satisfying the
+// FieldMap built from the fields of prototype's struct type, and
+// decodes it into a new value of that struct type. prototype may be a
+// struct or a pointer to a struct; only its type is used, and the
+// coerced result is always a struct value, never a pointer. Each field is matched
+// by a `schema:"name,optional"` tag, or by its lowercased Go field
+// name when no tag is present. Nested structs, pointers (left nil
+// when optional and absent), slices and maps are all supported,
+// built from the corresponding Go field type.
+func Struct(prototype interface{}) Checker {
+	t := reflect.TypeOf(prototype)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic("schema.Struct requires a struct or a pointer to a struct")
+	}
+
+	fields := make(Fields)
+	indexes := make(map[string]int)
+	var optional Optional
+	for i := 0; i != t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// unexported field
+			continue
+		}
+		name := strings.ToLower(f.Name)
+		isOptional := false
+		if tag := f.Tag.Get("schema"); tag != "" {
+			parts := strings.Split(tag, ",")
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			for _, opt := range parts[1:] {
+				if opt == "optional" {
+					isOptional = true
+				}
+			}
+		}
+		fields[name] = checkerForType(f.Type)
+		indexes[name] = i
+		if isOptional {
+			optional = append(optional, name)
+		}
+	}
+	return structC{t, indexes, FieldMap(fields, optional)}
+}
+
+type structC struct {
+	t       reflect.Type
+	indexes map[string]int
+	fields  Checker
+}
+
+func (c structC) Coerce(v interface{}, path []PathStep) (interface{}, os.Error) {
+	out, err := c.fields.Coerce(v, path)
+	if err != nil {
+		return nil, err
+	}
+	m := out.(map[string]interface{})
+
+	result := reflect.New(c.t).Elem()
+	for name, i := range c.indexes {
+		newv, ok := m[name]
+		if !ok {
+			continue
+		}
+		setField(result.Field(i), newv)
+	}
+	return result.Interface(), nil
+}
+
+// checkerForType picks the Checker that matches a struct field's Go
+// type, recursing into slices, maps, structs and pointers.
+func checkerForType(t reflect.Type) Checker {
+	switch t.Kind() {
+	case reflect.Bool:
+		return Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Int()
+	case reflect.Float32, reflect.Float64:
+		return Float()
+	case reflect.String:
+		return String()
+	case reflect.Slice:
+		return List(checkerForType(t.Elem()))
+	case reflect.Map:
+		return Map(checkerForType(t.Key()), checkerForType(t.Elem()))
+	case reflect.Struct:
+		return Struct(reflect.New(t).Elem().Interface())
+	case reflect.Ptr:
+		return checkerForType(t.Elem())
+	case reflect.Interface:
+		return Any()
+	}
+	panic("schema.Struct: unsupported field type " + t.String())
+}
+
+// setField writes newv, as produced by checkerForType's Checker, into
+// field, converting numeric kinds and recursing into pointers, slices
+// and maps built from Go field types.
+func setField(field reflect.Value, newv interface{}) {
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		setField(ptr.Elem(), newv)
+		field.Set(ptr)
+		return
+	}
+	rv := reflect.ValueOf(newv)
+	switch {
+	case field.Kind() == reflect.Slice && rv.Kind() == reflect.Slice:
+		out := reflect.MakeSlice(field.Type(), rv.Len(), rv.Len())
+		for i := 0; i != rv.Len(); i++ {
+			setField(out.Index(i), rv.Index(i).Interface())
+		}
+		field.Set(out)
+	case field.Kind() == reflect.Map && rv.Kind() == reflect.Map:
+		out := reflect.MakeMap(field.Type())
+		for _, k := range rv.MapKeys() {
+			kv := reflect.New(field.Type().Key()).Elem()
+			setField(kv, k.Interface())
+			vv := reflect.New(field.Type().Elem()).Elem()
+			setField(vv, rv.MapIndex(k).Interface())
+			out.SetMapIndex(kv, vv)
+		}
+		field.Set(out)
+	case field.Kind() == reflect.Struct && rv.Kind() == reflect.Struct:
+		field.Set(rv)
+	default:
+		field.Set(rv.Convert(field.Type()))
+	}
+}
+
+// loadError reports a problem with a document given to Load or
+// LoadBytes, as opposed to a coercion failure against one.
+type loadError string
+
+func (e loadError) String() string {
+	return string(e)
+}
+
+// CheckerBuilder builds a Checker out of the fields of a document
+// node (excluding its "type" entry), for use with Register.
+type CheckerBuilder func(fields map[string]interface{}) (Checker, os.Error)
+
+var registry = make(map[string]CheckerBuilder)
+
+// Register adds typeName as a recognised "type" value in documents
+// given to Load and LoadBytes. When typeName is found, builder is
+// called with the remaining fields of that document node. It is
+// meant to be called from the init function of packages that extend
+// the vocabulary understood by Load.
+func Register(typeName string, builder CheckerBuilder) {
+	registry[typeName] = builder
+}
+
+// Load reads a schema definition from r, in the format described by
+// LoadBytes, and returns the Checker it describes.
+func Load(r io.Reader) (Checker, os.Error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return LoadBytes(data)
+}
+
+// LoadBytes parses a schema definition written as JSON, such as:
+//
+//     {"type": "map", "fields": {
+//         "name": {"type": "string"},
+//         "port": {"type": "int", "min": 1, "max": 65535, "optional": true}
+//     }}
+//
+// and returns the tree of Checkers it describes. This package has no
+// YAML support of its own: callers whose schemas are authored as YAML
+// (e.g. config.yaml files in the sibling package) need to convert them
+// to JSON first, with a YAML library that can decode onto
+// interface{}/map[string]interface{}, before calling Load or
+// LoadBytes. Recognised "type" values are any, const, bool, int,
+// float, string, list, map, fieldmap and oneof, plus whatever has been
+// added with Register.
+// A field's "optional" entry, if true, adds that field's name to its
+// enclosing fieldmap's Optional list; a fieldmap's own "strict" entry,
+// if true, builds it with StrictFieldMap instead of FieldMap.
+func LoadBytes(data []byte) (Checker, os.Error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, loadError("schema: " + err.String())
+	}
+	return buildChecker(doc)
+}
+
+func buildChecker(node interface{}) (Checker, os.Error) {
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, loadError("schema: expected a document node, got a " + fmt.Sprintf("%#v", node))
+	}
+	typ, _ := m["type"].(string)
+	switch typ {
+	case "any":
+		return Any(), nil
+	case "const":
+		return Const(m["value"]), nil
+	case "bool":
+		return Bool(), nil
+	case "int":
+		return buildIntChecker(m), nil
+	case "float":
+		return buildFloatChecker(m), nil
+	case "string":
+		return buildStringChecker(m)
+	case "list":
+		elem, err := buildField(m["elem"])
+		if err != nil {
+			return nil, err
+		}
+		return List(elem), nil
+	case "map":
+		if _, ok := m["fields"]; ok {
+			return buildFieldMapChecker(m)
+		}
+		key, err := buildField(m["key"])
+		if err != nil {
+			return nil, err
+		}
+		value, err := buildField(m["value"])
+		if err != nil {
+			return nil, err
+		}
+		return Map(key, value), nil
+	case "fieldmap":
+		return buildFieldMapChecker(m)
+	case "oneof":
+		return buildOneOfChecker(m)
+	}
+	if builder, ok := registry[typ]; ok {
+		return builder(m)
+	}
+	return nil, loadError("schema: unknown type " + fmt.Sprintf("%q", typ))
+}
+
+func buildField(node interface{}) (Checker, os.Error) {
+	if node == nil {
+		return nil, loadError("schema: missing field definition")
+	}
+	return buildChecker(node)
+}
+
+func buildIntChecker(m map[string]interface{}) Checker {
+	minf, hasMin := numField(m, "min")
+	maxf, hasMax := numField(m, "max")
+	if !hasMin && !hasMax {
+		return Int()
+	}
+	// The unbounded side is built as an int64 constant directly,
+	// rather than through the float64 numField values: math.MaxInt64
+	// isn't exactly representable as float64 (it rounds up to 2^63),
+	// and converting that back from float64 would wrap around to the
+	// wrong int64 value.
+	min := int64(math.MinInt64)
+	if hasMin {
+		min = int64(minf)
+	}
+	max := int64(math.MaxInt64)
+	if hasMax {
+		max = int64(maxf)
+	}
+	return IntRange(min, max)
+}
+
+func buildFloatChecker(m map[string]interface{}) Checker {
+	min, hasMin := numField(m, "min")
+	max, hasMax := numField(m, "max")
+	if !hasMin && !hasMax {
+		return Float()
+	}
+	if !hasMin {
+		min = -math.MaxFloat64
+	}
+	if !hasMax {
+		max = math.MaxFloat64
+	}
+	return FloatRange(min, max)
+}
+
+func numField(m map[string]interface{}, key string) (float64, bool) {
+	n, ok := m[key].(float64)
+	return n, ok
+}
+
+func buildStringChecker(m map[string]interface{}) (Checker, os.Error) {
+	if pattern, ok := m["pattern"].(string); ok {
+		expr, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, loadError("schema: invalid pattern: " + err.String())
+		}
+		return stringMatchingC{expr}, nil
+	}
+	min, hasMin := numField(m, "min")
+	max, hasMax := numField(m, "max")
+	if hasMin || hasMax {
+		if !hasMin {
+			min = 0
+		}
+		if !hasMax {
+			max = math.MaxInt32
+		}
+		return StringLength(int(min), int(max)), nil
+	}
+	return String(), nil
+}
+
+func buildFieldMapChecker(m map[string]interface{}) (Checker, os.Error) {
+	fieldNodes, _ := m["fields"].(map[string]interface{})
+	fields := make(Fields, len(fieldNodes))
+	var optional Optional
+	for name, node := range fieldNodes {
+		checker, err := buildChecker(node)
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = checker
+		if fnode, _ := node.(map[string]interface{}); fnode != nil {
+			if isOptional, _ := fnode["optional"].(bool); isOptional {
+				optional = append(optional, name)
+			}
+		}
+	}
+	if strict, _ := m["strict"].(bool); strict {
+		return StrictFieldMap(fields, optional), nil
+	}
+	return FieldMap(fields, optional), nil
+}
+
+func buildOneOfChecker(m map[string]interface{}) (Checker, os.Error) {
+	nodes, _ := m["options"].([]interface{})
+	options := make([]Checker, len(nodes))
+	for i, node := range nodes {
+		checker, err := buildChecker(node)
+		if err != nil {
+			return nil, err
+		}
+		options[i] = checker
+	}
+	return OneOf(options...), nil
+}
@@ -0,0 +1,87 @@
+package schema
+
+import "testing"
+
+func TestIntRangeInclusiveBounds(t *testing.T) {
+	checker := IntRange(1, 10)
+	if _, err := checker.Coerce(int64(1), nil); err != nil {
+		t.Errorf("Coerce(1): %v, want none (min is inclusive)", err)
+	}
+	if _, err := checker.Coerce(int64(10), nil); err != nil {
+		t.Errorf("Coerce(10): %v, want none (max is inclusive)", err)
+	}
+	if _, err := checker.Coerce(int64(0), nil); err == nil {
+		t.Errorf("Coerce(0): got no error, want one (below min)")
+	}
+	if _, err := checker.Coerce(int64(11), nil); err == nil {
+		t.Errorf("Coerce(11): got no error, want one (above max)")
+	}
+}
+
+func TestFloatRangeInclusiveBounds(t *testing.T) {
+	checker := FloatRange(1.0, 10.0)
+	if _, err := checker.Coerce(1.0, nil); err != nil {
+		t.Errorf("Coerce(1.0): %v, want none (min is inclusive)", err)
+	}
+	if _, err := checker.Coerce(10.0, nil); err != nil {
+		t.Errorf("Coerce(10.0): %v, want none (max is inclusive)", err)
+	}
+	if _, err := checker.Coerce(0.5, nil); err == nil {
+		t.Errorf("Coerce(0.5): got no error, want one (below min)")
+	}
+}
+
+func TestStringMatching(t *testing.T) {
+	checker := StringMatching(`^[a-z]+$`)
+	if _, err := checker.Coerce("abc", nil); err != nil {
+		t.Errorf("Coerce(%q): %v, want none", "abc", err)
+	}
+	if _, err := checker.Coerce("ABC", nil); err == nil {
+		t.Errorf("Coerce(%q): got no error, want one", "ABC")
+	}
+}
+
+func TestStringLengthInclusiveBounds(t *testing.T) {
+	checker := StringLength(2, 4)
+	if _, err := checker.Coerce("ab", nil); err != nil {
+		t.Errorf("Coerce(%q): %v, want none (min is inclusive)", "ab", err)
+	}
+	if _, err := checker.Coerce("abcd", nil); err != nil {
+		t.Errorf("Coerce(%q): %v, want none (max is inclusive)", "abcd", err)
+	}
+	if _, err := checker.Coerce("a", nil); err == nil {
+		t.Errorf("Coerce(%q): got no error, want one (below min)", "a")
+	}
+	if _, err := checker.Coerce("abcde", nil); err == nil {
+		t.Errorf("Coerce(%q): got no error, want one (above max)", "abcde")
+	}
+}
+
+func TestListLengthInclusiveBounds(t *testing.T) {
+	checker := ListLength(1, 2)
+	if _, err := checker.Coerce([]interface{}{"a"}, nil); err != nil {
+		t.Errorf("Coerce with 1 elem: %v, want none (min is inclusive)", err)
+	}
+	if _, err := checker.Coerce([]interface{}{"a", "b"}, nil); err != nil {
+		t.Errorf("Coerce with 2 elems: %v, want none (max is inclusive)", err)
+	}
+	if _, err := checker.Coerce([]interface{}{}, nil); err == nil {
+		t.Errorf("Coerce with 0 elems: got no error, want one (below min)")
+	}
+	if _, err := checker.Coerce([]interface{}{"a", "b", "c"}, nil); err == nil {
+		t.Errorf("Coerce with 3 elems: got no error, want one (above max)")
+	}
+}
+
+func TestMapLengthInclusiveBounds(t *testing.T) {
+	checker := MapLength(1, 1)
+	if _, err := checker.Coerce(map[string]interface{}{"a": 1}, nil); err != nil {
+		t.Errorf("Coerce with 1 entry: %v, want none", err)
+	}
+	if _, err := checker.Coerce(map[string]interface{}{}, nil); err == nil {
+		t.Errorf("Coerce with 0 entries: got no error, want one (below min)")
+	}
+	if _, err := checker.Coerce(map[string]interface{}{"a": 1, "b": 2}, nil); err == nil {
+		t.Errorf("Coerce with 2 entries: got no error, want one (above max)")
+	}
+}
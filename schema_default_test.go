@@ -0,0 +1,41 @@
+package schema
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDefaultSubstitutesOnNil(t *testing.T) {
+	checker := Default(int64(8080), Int())
+	out, err := checker.Coerce(nil, nil)
+	if err != nil {
+		t.Fatalf("Coerce(nil): %v", err)
+	}
+	if out != int64(8080) {
+		t.Errorf("out = %#v, want %#v", out, int64(8080))
+	}
+}
+
+func TestDefaultLeavesPresentValueAlone(t *testing.T) {
+	checker := Default(int64(8080), Int())
+	out, err := checker.Coerce(int64(80), nil)
+	if err != nil {
+		t.Fatalf("Coerce(80): %v", err)
+	}
+	if out != int64(80) {
+		t.Errorf("out = %#v, want %#v", out, int64(80))
+	}
+}
+
+func TestWithHookTransformsOnSuccess(t *testing.T) {
+	checker := WithHook(String(), func(v interface{}, path []PathStep) (interface{}, os.Error) {
+		return v.(string) + "!", nil
+	})
+	out, err := checker.Coerce("hi", nil)
+	if err != nil {
+		t.Fatalf("Coerce: %v", err)
+	}
+	if out != "hi!" {
+		t.Errorf("out = %#v, want %#v", out, "hi!")
+	}
+}
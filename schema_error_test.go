@@ -0,0 +1,45 @@
+package schema
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWithHookFailurePreservesMessage(t *testing.T) {
+	checker := WithHook(String(), func(v interface{}, path []PathStep) (interface{}, os.Error) {
+		return nil, loadError("not a valid duration")
+	})
+	_, errs := CoerceAll(checker, "garbage")
+	if len(errs) != 1 {
+		t.Fatalf("len(errs) = %d, want 1", len(errs))
+	}
+	if !strings.Contains(errs[0].String(), "not a valid duration") {
+		t.Errorf("error %q doesn't preserve the hook's message", errs[0].String())
+	}
+}
+
+func TestReportIsSideBySide(t *testing.T) {
+	checker := FieldMap(Fields{"port": Int()}, nil)
+	var buf bytes.Buffer
+	Report(&buf, map[string]interface{}{"port": "not a number"}, checker)
+	out := buf.String()
+	if !strings.Contains(out, "expected:") || !strings.Contains(out, "got:") {
+		t.Errorf("Report output doesn't look side-by-side:\n%s", out)
+	}
+	if !strings.Contains(out, ".port:") {
+		t.Errorf("Report output doesn't render the field path correctly (want single-dot \".port:\"):\n%s", out)
+	}
+}
+
+func TestErrorPathHasNoDoubleDot(t *testing.T) {
+	checker := FieldMap(Fields{"name": Int()}, nil)
+	_, err := checker.Coerce(map[string]interface{}{"name": "nope"}, nil)
+	if err == nil {
+		t.Fatalf("Coerce: got no error")
+	}
+	if !strings.HasPrefix(err.String(), ".name:") {
+		t.Errorf("error %q doesn't start with \".name:\" (got a double-dotted path?)", err.String())
+	}
+}
@@ -0,0 +1,77 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFieldMapAggregatesAllErrors(t *testing.T) {
+	checker := FieldMap(Fields{
+		"a": Int(),
+		"b": Int(),
+		"c": Int(),
+	}, nil)
+	_, err := checker.Coerce(map[string]interface{}{
+		"a": "not an int",
+		"b": int64(1),
+		"c": "also not an int",
+	}, nil)
+	me, ok := err.(*MultiError)
+	if !ok {
+		t.Fatalf("err is %T, want *MultiError", err)
+	}
+	if len(me.Errors) != 2 {
+		t.Fatalf("len(me.Errors) = %d, want 2", len(me.Errors))
+	}
+}
+
+func TestFieldMapErrorOrderIsDeterministic(t *testing.T) {
+	checker := FieldMap(Fields{
+		"zebra": Int(),
+		"apple": Int(),
+		"mango": Int(),
+	}, nil)
+	bad := map[string]interface{}{
+		"zebra": "x",
+		"apple": "x",
+		"mango": "x",
+	}
+	_, first := checker.Coerce(bad, nil)
+	for i := 0; i < 20; i++ {
+		_, next := checker.Coerce(bad, nil)
+		if first.String() != next.String() {
+			t.Fatalf("error order changed between runs:\n%s\nvs\n%s", first.String(), next.String())
+		}
+	}
+}
+
+func TestStrictFieldMapRejectsUnknownKeys(t *testing.T) {
+	checker := StrictFieldMap(Fields{"name": String()}, nil)
+	_, err := checker.Coerce(map[string]interface{}{
+		"name":  "foo",
+		"extra": "bar",
+	}, nil)
+	if err == nil {
+		t.Fatalf("Coerce with unknown key: got no error")
+	}
+	if !strings.Contains(err.String(), "extra") {
+		t.Errorf("error %q doesn't mention the unknown key", err.String())
+	}
+}
+
+func TestStrictFieldMapUnknownKeyOrderIsDeterministic(t *testing.T) {
+	checker := StrictFieldMap(Fields{"name": String()}, nil)
+	bad := map[string]interface{}{
+		"name":  "foo",
+		"zebra": 1,
+		"apple": 2,
+		"mango": 3,
+	}
+	_, first := checker.Coerce(bad, nil)
+	for i := 0; i < 20; i++ {
+		_, next := checker.Coerce(bad, nil)
+		if first.String() != next.String() {
+			t.Fatalf("unknown key error order changed between runs:\n%s\nvs\n%s", first.String(), next.String())
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+type innerStruct struct {
+	Host string
+	Port int64
+}
+
+type outerStruct struct {
+	Name    string
+	Inner   innerStruct
+	Tags    []string
+	Counts  map[string]int64
+	Nick    *string `schema:"nick,optional"`
+	Comment string  `schema:"comment,optional"`
+}
+
+func TestStructDecodesNestedPointerSliceAndMap(t *testing.T) {
+	checker := Struct(outerStruct{})
+	input := map[string]interface{}{
+		"name": "db1",
+		"inner": map[string]interface{}{
+			"host": "localhost",
+			"port": int64(5432),
+		},
+		"tags":   []interface{}{"a", "b"},
+		"counts": map[interface{}]interface{}{"x": int64(1)},
+		"nick":   "db",
+	}
+	out, err := checker.Coerce(input, nil)
+	if err != nil {
+		t.Fatalf("Coerce: %v", err)
+	}
+	got := out.(outerStruct)
+	want := outerStruct{
+		Name:   "db1",
+		Inner:  innerStruct{Host: "localhost", Port: 5432},
+		Tags:   []string{"a", "b"},
+		Counts: map[string]int64{"x": 1},
+	}
+	if got.Name != want.Name || got.Inner != want.Inner || !reflect.DeepEqual(got.Tags, want.Tags) || !reflect.DeepEqual(got.Counts, want.Counts) {
+		t.Fatalf("Coerce result = %#v, want %#v (ignoring Nick)", got, want)
+	}
+	if got.Nick == nil || *got.Nick != "db" {
+		t.Errorf("Nick = %v, want pointer to \"db\"", got.Nick)
+	}
+}
+
+func TestStructLeavesAbsentOptionalPointerNil(t *testing.T) {
+	checker := Struct(outerStruct{})
+	input := map[string]interface{}{
+		"name": "db1",
+		"inner": map[string]interface{}{
+			"host": "localhost",
+			"port": int64(5432),
+		},
+		"tags":   []interface{}{},
+		"counts": map[interface{}]interface{}{},
+	}
+	out, err := checker.Coerce(input, nil)
+	if err != nil {
+		t.Fatalf("Coerce: %v", err)
+	}
+	got := out.(outerStruct)
+	if got.Nick != nil {
+		t.Errorf("Nick = %v, want nil", *got.Nick)
+	}
+}
+
+func TestStructMissingRequiredFieldReportsErrorInsteadOfPanicking(t *testing.T) {
+	checker := Struct(outerStruct{})
+	input := map[string]interface{}{
+		"inner": map[string]interface{}{
+			"host": "localhost",
+			"port": int64(5432),
+		},
+		"tags":   []interface{}{},
+		"counts": map[interface{}]interface{}{},
+	}
+	_, err := checker.Coerce(input, nil)
+	if err == nil {
+		t.Fatalf("Coerce with missing required field \"name\": got no error")
+	}
+}
@@ -0,0 +1,65 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func mustLoad(t *testing.T, doc string) Checker {
+	checker, err := LoadBytes([]byte(doc))
+	if err != nil {
+		t.Fatalf("LoadBytes(%q): %v", doc, err)
+	}
+	return checker
+}
+
+func TestLoadIntRangeWithOnlyMin(t *testing.T) {
+	checker := mustLoad(t, `{"type": "int", "min": 1}`)
+	if _, err := checker.Coerce(int64(1), nil); err != nil {
+		t.Errorf("Coerce(1): got error %v, want none", err)
+	}
+	if _, err := checker.Coerce(int64(1000000), nil); err != nil {
+		t.Errorf("Coerce(1000000): got error %v, want none", err)
+	}
+	if _, err := checker.Coerce(int64(0), nil); err == nil {
+		t.Errorf("Coerce(0): got no error, want one (below min)")
+	}
+}
+
+func TestLoadIntRangeWithOnlyMax(t *testing.T) {
+	checker := mustLoad(t, `{"type": "int", "max": 65535}`)
+	if _, err := checker.Coerce(int64(-1000000), nil); err != nil {
+		t.Errorf("Coerce(-1000000): got error %v, want none", err)
+	}
+	if _, err := checker.Coerce(int64(65536), nil); err == nil {
+		t.Errorf("Coerce(65536): got no error, want one (above max)")
+	}
+}
+
+func TestLoadFieldMap(t *testing.T) {
+	checker := mustLoad(t, `{"type": "map", "fields": {
+		"name": {"type": "string"},
+		"port": {"type": "int", "min": 1, "max": 65535, "optional": true}
+	}}`)
+	out, err := checker.Coerce(map[string]interface{}{"name": "foo"}, nil)
+	if err != nil {
+		t.Fatalf("Coerce: %v", err)
+	}
+	m := out.(map[string]interface{})
+	if m["name"] != "foo" {
+		t.Errorf("name = %#v, want %#v", m["name"], "foo")
+	}
+	if _, ok := m["port"]; ok {
+		t.Errorf("port present in output, want absent (optional and missing)")
+	}
+}
+
+func TestLoadUnknownType(t *testing.T) {
+	_, err := LoadBytes([]byte(`{"type": "bogus"}`))
+	if err == nil {
+		t.Fatalf("LoadBytes with unknown type: got no error")
+	}
+	if !strings.Contains(err.String(), "bogus") {
+		t.Errorf("error %q doesn't mention the unknown type", err.String())
+	}
+}